@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/setting"
+
+	"gopkg.in/macaron.v1"
+)
+
+// registerRequestMiddlewares installs the CORS and RequestTracing
+// middlewares on m, in that order. CORS has to run before auth so it can
+// short-circuit preflights before any permission check sees them;
+// RequestTracing has to run before routing populates the route operation
+// name. HTTPServer's macaron setup (newMacaron) calls this ahead of
+// registering auth and the route table.
+func registerRequestMiddlewares(m *macaron.Macaron, cfg *setting.Cfg) {
+	m.Use(middleware.CORS(cfg))
+	m.Use(newRequestTracingMiddleware(cfg))
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/grafana/grafana/pkg/services/accesscontrol"
@@ -9,6 +10,20 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// callCORSPreflight issues method against getOrgPreferencesURL with an
+// Origin header set, so that middleware.CORS (registered ahead of auth,
+// see registerRequestMiddlewares) actually has a cross-origin request to
+// react to - without it, every preflight falls straight through to auth
+// the same as a same-origin request would.
+func callCORSPreflight(server http.Handler, method, url string, t *testing.T) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, url, nil)
+	req.Header.Set("Origin", "https://example.org")
+	rec := httptest.NewRecorder()
+	server.ServeHTTP(rec, req)
+	return rec
+}
+
 var (
 	getOrgPreferencesURL = "/api/org/preferences/"
 )
@@ -55,3 +70,55 @@ func TestAPIEndpoint_GetCurrentOrgPreferences_AccessControl(t *testing.T) {
 		assert.Equal(t, http.StatusForbidden, response.Code)
 	})
 }
+
+func TestAPIEndpoint_GetCurrentOrgPreferences_CORSPreflight_LegacyAccessControl(t *testing.T) {
+	sc := setupHTTPServer(t, false)
+
+	_, err := sc.db.CreateOrgWithMember("TestOrg", testUserID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		signIn     func()
+		wantStatus int
+	}{
+		{"Viewer preflight succeeds even though the real request would be forbidden", func() { setInitCtxSignedInViewer(sc.initCtx) }, http.StatusNoContent},
+		{"Org Admin preflight succeeds", func() { setInitCtxSignedInOrgAdmin(sc.initCtx) }, http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.signIn()
+			response := callCORSPreflight(sc.server, http.MethodOptions, getOrgPreferencesURL, t)
+			assert.Equal(t, tt.wantStatus, response.Code)
+			assert.Contains(t, response.Header().Get("Access-Control-Allow-Methods"), http.MethodGet)
+		})
+	}
+}
+
+func TestAPIEndpoint_GetCurrentOrgPreferences_CORSPreflight_AccessControl(t *testing.T) {
+	sc := setupHTTPServer(t, true)
+	setInitCtxSignedInViewer(sc.initCtx)
+
+	_, err := sc.db.CreateOrgWithMember("TestOrg", testUserID)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		permissions []*accesscontrol.Permission
+		method      string
+		wantStatus  int
+	}{
+		{"Preflight bypasses RBAC denial", []*accesscontrol.Permission{{Action: "orgs:invalid"}}, http.MethodOptions, http.StatusNoContent},
+		{"Real request still enforces RBAC", []*accesscontrol.Permission{{Action: "orgs:invalid"}}, http.MethodGet, http.StatusForbidden},
+		{"Real request succeeds with correct permissions", []*accesscontrol.Permission{{Action: ActionOrgsPreferencesRead, Scope: ScopeOrgsAll}}, http.MethodGet, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setAccessControlPermissions(sc.acmock, tt.permissions)
+			response := callCORSPreflight(sc.server, tt.method, getOrgPreferencesURL, t)
+			assert.Equal(t, tt.wantStatus, response.Code)
+		})
+	}
+}
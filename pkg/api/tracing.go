@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/services/contexthandler"
+	"github.com/grafana/grafana/pkg/setting"
+	"go.opentelemetry.io/otel/attribute"
+
+	"gopkg.in/macaron.v1"
+)
+
+// newRequestTracingMiddleware is what HTTPServer's macaron setup (newMacaron)
+// registers instead of calling middleware.RequestTracingFromConfig directly,
+// so that org/user span attributes keep working without pkg/middleware
+// depending on contexthandler.
+func newRequestTracingMiddleware(cfg *setting.Cfg) macaron.Handler {
+	return middleware.RequestTracingFromConfig(cfg, middleware.WithAttributeExtractor(signedInUserAttributeExtractor))
+}
+
+// signedInUserAttributeExtractor adds grafana.org_id and grafana.user_id to
+// the request span once the signed-in user is known. It's registered as a
+// middleware.WithAttributeExtractor, which runs after auth, rather than
+// living in pkg/middleware directly: contexthandler sits above middleware in
+// the dependency graph, so pulling the signed-in user into middleware
+// itself would create an import cycle.
+func signedInUserAttributeExtractor(req *http.Request) []attribute.KeyValue {
+	reqCtx := contexthandler.FromContext(req.Context())
+	if reqCtx == nil || reqCtx.SignedInUser == nil {
+		return nil
+	}
+
+	return []attribute.KeyValue{
+		attribute.Int64("grafana.org_id", reqCtx.SignedInUser.OrgID),
+		attribute.Int64("grafana.user_id", reqCtx.SignedInUser.UserID),
+	}
+}
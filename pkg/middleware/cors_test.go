@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+	"gopkg.in/macaron.v1"
+)
+
+func newCORSTestServer(t *testing.T, authMiddleware macaron.Handler) *macaron.Macaron {
+	t.Helper()
+
+	raw, err := ini.Load([]byte(`
+[security.cors]
+allow_origins = https://example.org
+allow_headers = Origin, Content-Type
+`))
+	require.NoError(t, err)
+	cfg := &setting.Cfg{Raw: raw}
+	// CORS reads cfg.Security, which package setting fills in from
+	// [security.cors] with an unexported reader; set what that reader
+	// produces for raw (allow_methods unset, so the default method list).
+	cfg.Security = setting.SecuritySettings{
+		CORSAllowOrigins: []string{"https://example.org"},
+		CORSAllowMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+		CORSAllowHeaders: []string{"Origin", "Content-Type"},
+	}
+
+	m := macaron.New()
+	m.Use(CORS(cfg))
+	m.Use(authMiddleware)
+	m.Get("/api/org/preferences", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})
+	return m
+}
+
+func denyingAuthMiddleware(res http.ResponseWriter, req *http.Request, c *macaron.Context) {
+	res.WriteHeader(http.StatusForbidden)
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	tests := []struct {
+		name   string
+		origin string
+	}{
+		{"preflight from an allowed origin bypasses auth entirely", "https://example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newCORSTestServer(t, denyingAuthMiddleware)
+
+			req := httptest.NewRequest(http.MethodOptions, "/api/org/preferences", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+
+			m.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusNoContent, rec.Code)
+			assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), http.MethodGet)
+			assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+		})
+	}
+}
+
+func TestCORS_PreflightFromDisallowedOrigin_StillReachesAuth(t *testing.T) {
+	m := newCORSTestServer(t, denyingAuthMiddleware)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/org/preferences", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	rec := httptest.NewRecorder()
+
+	m.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestCORS_RealRequest_StillEnforcesAuth(t *testing.T) {
+	m := newCORSTestServer(t, denyingAuthMiddleware)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/org/preferences", nil)
+	req.Header.Set("Origin", "https://example.org")
+	rec := httptest.NewRecorder()
+
+	m.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, "https://example.org", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_NoOriginHeader_PassesThrough(t *testing.T) {
+	var authRan bool
+	m := newCORSTestServer(t, func(res http.ResponseWriter, req *http.Request, c *macaron.Context) {
+		authRan = true
+		c.Next()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/org/preferences", nil)
+	rec := httptest.NewRecorder()
+
+	m.ServeHTTP(rec, req)
+
+	assert.True(t, authRan)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
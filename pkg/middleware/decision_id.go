@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/oklog/ulid/v2"
+)
+
+type decisionIDContextKey struct{}
+
+var decisionIDKey = decisionIDContextKey{}
+
+// DecisionIDHeaderName is the response header a decision ID is exposed
+// under, so that a client report ("I got a 403") can be correlated back to
+// the access-control evaluation, trace and audit-log entry that produced it.
+const DecisionIDHeaderName = "X-Grafana-Decision-Id"
+
+// newDecisionID generates a new, sortable decision ID. It uses the same
+// ULID format Grafana already relies on elsewhere, via ulid.Make and its
+// default entropy source.
+func newDecisionID() string {
+	return ulid.Make().String()
+}
+
+// WithDecisionID returns a copy of ctx carrying decisionID, so that it can be
+// read back later in the request lifecycle via DecisionIDFromContext.
+func WithDecisionID(ctx context.Context, decisionID string) context.Context {
+	return context.WithValue(ctx, decisionIDKey, decisionID)
+}
+
+// DecisionIDFromContext returns the decision ID attached to ctx by
+// RequestTracing, if any. Callers of accesscontrol.Evaluate and
+// accesscontrol.LogDecision use this to get the ID that ties their audit-log
+// entries back to the request's trace.
+func DecisionIDFromContext(ctx context.Context) (string, bool) {
+	if val := ctx.Value(decisionIDKey); val != nil {
+		id, ok := val.(string)
+		return id, ok
+	}
+
+	return "", false
+}
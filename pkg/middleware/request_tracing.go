@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/grafana/grafana/pkg/infra/tracing"
+	"github.com/grafana/grafana/pkg/setting"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
 	trace "go.opentelemetry.io/otel/trace"
@@ -21,6 +24,13 @@ type contextKey struct{}
 
 var routeOperationNameKey = contextKey{}
 
+func init() {
+	// Extend the default TraceContext-only propagator with W3C Baggage
+	// support, so baggage members set upstream (or via RequestTracing
+	// itself) survive outgoing requests and can be copied onto spans.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+}
+
 // ProvideRouteOperationName creates a named middleware responsible for populating
 // the context with the route operation name that can be used later in the request pipeline.
 // Implements routing.RegisterNamedMiddleware.
@@ -41,7 +51,191 @@ func RouteOperationNameFromContext(ctx context.Context) (string, bool) {
 	return "", false
 }
 
+// TracingOption customizes the behaviour of the RequestTracing middleware.
+type TracingOption func(*tracingOptions)
+
+type tracingOptions struct {
+	publicEndpointFn    func(*http.Request) bool
+	baggageKeys         map[string]struct{}
+	attributeExtractors []func(*http.Request) []attribute.KeyValue
+	filters             []func(*http.Request) bool
+}
+
+type samplingRatioContextKey struct{}
+
+var samplingRatioKey = samplingRatioContextKey{}
+
+// routeSamplingHint is what ProvideRouteSamplingRatio stores in context: the
+// ratio RequestTracing should hint at, plus the route name it came from, for
+// the "grafana.sampling_ratio_route" span attribute.
+type routeSamplingHint struct {
+	routeName string
+	ratio     float64
+}
+
+// ProvideRouteSamplingRatio creates a middleware that stashes a sampling
+// ratio hint for this route in the request context, for RequestTracing to
+// pick up when starting the request span.
+//
+// Unlike ProvideRouteOperationName, this is NOT a routing.RegisterNamedMiddleware
+// factory - that interface is func(name string) macaron.Handler, with a
+// single route name as input, which doesn't leave room for a per-route
+// ratio. Register it directly at the route definition instead, e.g.
+// r.Get("/api/org/preferences", middleware.ProvideRouteSamplingRatio("org-preferences", 0.1), ...).
+func ProvideRouteSamplingRatio(name string, ratio float64) macaron.Handler {
+	return func(res http.ResponseWriter, req *http.Request, c *macaron.Context) {
+		ctx := context.WithValue(c.Req.Context(), samplingRatioKey, routeSamplingHint{routeName: name, ratio: ratio})
+		c.Req = c.Req.WithContext(ctx)
+	}
+}
+
+// SamplingRatioFromContext receives the route's sampling ratio hint from
+// context, if set via ProvideRouteSamplingRatio.
+func SamplingRatioFromContext(ctx context.Context) (float64, bool) {
+	if hint, ok := ctx.Value(samplingRatioKey).(routeSamplingHint); ok {
+		return hint.ratio, true
+	}
+
+	return 0, false
+}
+
+// samplingRouteNameFromContext receives the route name ProvideRouteSamplingRatio
+// was registered with, if set.
+func samplingRouteNameFromContext(ctx context.Context) (string, bool) {
+	if hint, ok := ctx.Value(samplingRatioKey).(routeSamplingHint); ok {
+		return hint.routeName, true
+	}
+
+	return "", false
+}
+
+// WithFilter registers a predicate that decides whether a request is traced
+// at all. Unlike the route-operation-name check below, a filtered-out
+// request never gets a span started for it in the first place, following
+// the otelhttp Filters pattern.
+func WithFilter(filter func(*http.Request) bool) TracingOption {
+	return func(o *tracingOptions) {
+		o.filters = append(o.filters, filter)
+	}
+}
+
+// WithBaggageKeys configures an allowlist of W3C Baggage member keys that
+// are copied onto the request span as `baggage.<key>` attributes. Baggage
+// members not in this list are still propagated downstream, they are just
+// not added to the span.
+func WithBaggageKeys(keys ...string) TracingOption {
+	return func(o *tracingOptions) {
+		if o.baggageKeys == nil {
+			o.baggageKeys = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			o.baggageKeys[key] = struct{}{}
+		}
+	}
+}
+
+// WithAttributeExtractor registers an additional function that produces span
+// attributes from the request, e.g. to add domain-specific attributes
+// without forking this middleware. Extractors run after the rest of the
+// middleware chain (c.Next()) has returned, so they see whatever downstream
+// middlewares - auth included - added to the request context; this is the
+// hook callers should use to add things like the signed-in user's org/user
+// ID onto the span, without this package needing to depend on the service
+// that populates them.
+func WithAttributeExtractor(fn func(*http.Request) []attribute.KeyValue) TracingOption {
+	return func(o *tracingOptions) {
+		o.attributeExtractors = append(o.attributeExtractors, fn)
+	}
+}
+
+// WithPublicEndpoint marks every request handled by this middleware as coming
+// from an untrusted, public-facing endpoint: the incoming trace context is
+// never adopted as the parent of the request span, it is only attached as a
+// span link. Mirrors otelhttp's WithPublicEndpoint.
+func WithPublicEndpoint() TracingOption {
+	return WithPublicEndpointFn(func(*http.Request) bool { return true })
+}
+
+// WithPublicEndpointFn is like WithPublicEndpoint except that the decision is
+// made on a per-request basis, e.g. to only treat some path prefixes as
+// public. Mirrors otelhttp's WithPublicEndpointFn.
+func WithPublicEndpointFn(fn func(*http.Request) bool) TracingOption {
+	return func(o *tracingOptions) {
+		o.publicEndpointFn = fn
+	}
+}
+
+// RequestTracing is RequestTracingWithOptions with no options set, i.e. the
+// incoming trace context is always trusted and adopted as the parent of the
+// request span.
 func RequestTracing() macaron.Handler {
+	return RequestTracingWithOptions()
+}
+
+// defaultExcludedRoutes are never traced, regardless of configuration:
+// health checks and metrics scrapes are high-volume and not interesting to
+// trace, and the live websocket is long-lived and would otherwise hold a
+// span open for the lifetime of the connection.
+var defaultExcludedRoutes = []string{"/api/health", "/metrics", "/api/live/ws"}
+
+// RequestTracingFromConfig builds the RequestTracing middleware from
+// [tracing.opentelemetry] settings, turning `public_endpoint` /
+// `public_endpoint_path_prefixes` into the equivalent WithPublicEndpoint /
+// WithPublicEndpointFn options, and `excluded_routes` plus the built-in
+// health/metrics/live routes into a WithFilter option. extraOpts is appended
+// as-is, e.g. for a caller-supplied WithAttributeExtractor that needs
+// service dependencies this package doesn't have (the signed-in user, say).
+func RequestTracingFromConfig(cfg *setting.Cfg, extraOpts ...TracingOption) macaron.Handler {
+	var opts []TracingOption
+
+	if !cfg.Tracing.PublicEndpoint {
+		// no-op, default behaviour already trusts the incoming trace context
+	} else if prefixes := cfg.Tracing.PublicEndpointPathPrefixes; len(prefixes) == 0 {
+		opts = append(opts, WithPublicEndpoint())
+	} else {
+		opts = append(opts, WithPublicEndpointFn(func(req *http.Request) bool {
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(req.URL.Path, prefix) {
+					return true
+				}
+			}
+			return false
+		}))
+	}
+
+	excludedRoutes := append([]string{}, defaultExcludedRoutes...)
+	excludedRoutes = append(excludedRoutes, cfg.Tracing.ExcludedRoutes...)
+
+	excludedRegexes := make([]*regexp.Regexp, 0, len(excludedRoutes))
+	for _, pattern := range excludedRoutes {
+		if re, err := regexp.Compile(pattern); err == nil {
+			excludedRegexes = append(excludedRegexes, re)
+		}
+	}
+
+	opts = append(opts, WithFilter(func(req *http.Request) bool {
+		for _, re := range excludedRegexes {
+			if re.MatchString(req.URL.Path) {
+				return false
+			}
+		}
+		return true
+	}))
+
+	opts = append(opts, extraOpts...)
+
+	return RequestTracingWithOptions(opts...)
+}
+
+// RequestTracingWithOptions behaves like RequestTracing but allows callers to
+// opt into treating this Grafana instance as a public-facing endpoint, see
+// WithPublicEndpoint and WithPublicEndpointFn.
+func RequestTracingWithOptions(opts ...TracingOption) macaron.Handler {
+	o := &tracingOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	return func(res http.ResponseWriter, req *http.Request, c *macaron.Context) {
 		if strings.HasPrefix(c.Req.URL.Path, "/public/") ||
 			c.Req.URL.Path == "robots.txt" {
@@ -49,21 +243,89 @@ func RequestTracing() macaron.Handler {
 			return
 		}
 
+		// OPTIONS requests are never traced. CORS preflights from an allowed
+		// origin are answered by the CORS middleware earlier in the chain and
+		// don't get here; any other OPTIONS request is passed on without a
+		// span.
+		if req.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		for _, filter := range o.filters {
+			if !filter(req) {
+				c.Next()
+				return
+			}
+		}
+
 		rw := res.(macaron.ResponseWriter)
 
 		wireContext := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
-		ctx, span := tracing.Tracer.Start(req.Context(), fmt.Sprintf("HTTP %s %s", req.Method, req.URL.Path), trace.WithLinks(trace.LinkFromContext(wireContext)))
+
+		var startOpts []trace.SpanStartOption
+		if o.publicEndpointFn != nil && o.publicEndpointFn(req) {
+			startOpts = append(startOpts, trace.WithNewRoot(), trace.WithLinks(trace.LinkFromContext(wireContext)))
+			// WithNewRoot only affects the trace/span IDs, not arbitrary
+			// context values - but since we don't adopt wireContext as the
+			// base context below in this branch, any baggage it carried
+			// would otherwise be silently dropped instead of ending up on
+			// the span.
+			req = req.WithContext(baggage.ContextWithBaggage(req.Context(), baggage.FromContext(wireContext)))
+		} else {
+			req = req.WithContext(wireContext)
+		}
+
+		if ratio, ok := SamplingRatioFromContext(req.Context()); ok {
+			// The actual sampling decision is made by the configured
+			// sdktrace.Sampler, which is route-aware and reads these
+			// attributes from the span start attributes passed to Start; we
+			// just make the hint available here.
+			hintAttrs := []attribute.KeyValue{attribute.Float64("grafana.sampling_ratio_hint", ratio)}
+			if routeName, ok := samplingRouteNameFromContext(req.Context()); ok {
+				hintAttrs = append(hintAttrs, attribute.String("grafana.sampling_ratio_route", routeName))
+			}
+			startOpts = append(startOpts, trace.WithAttributes(hintAttrs...))
+		}
+
+		ctx, span := tracing.Tracer.Start(req.Context(), fmt.Sprintf("HTTP %s %s", req.Method, req.URL.Path), startOpts...)
+
+		decisionID := newDecisionID()
+		ctx = WithDecisionID(ctx, decisionID)
+		span.SetAttributes(attribute.String("grafana.ac.decision_id", decisionID))
+		res.Header().Set(DecisionIDHeaderName, decisionID)
+
+		for _, member := range baggage.FromContext(ctx).Members() {
+			if _, ok := o.baggageKeys[member.Key()]; ok {
+				span.SetAttributes(attribute.String("baggage."+member.Key(), member.Value()))
+			}
+		}
 
 		c.Req = req.WithContext(ctx)
 		c.Map(c.Req)
 
 		c.Next()
 
+		// Attribute extractors run after the rest of the chain so they can
+		// see context values downstream middlewares (e.g. auth) populated,
+		// such as the signed-in user.
+		for _, extractor := range o.attributeExtractors {
+			span.SetAttributes(extractor(c.Req)...)
+		}
+
 		// Only call span.End when a route operation name have been set,
 		// meaning that not set the span would not be reported.
 		if routeOperation, exists := RouteOperationNameFromContext(c.Req.Context()); exists {
 			defer span.End()
 			span.SetName(fmt.Sprintf("HTTP %s %s", req.Method, routeOperation))
+			// http.route carries the route operation name, not req.URL.Path,
+			// so that exemplar-based queries in Tempo/Jaeger don't fragment
+			// on high-cardinality path segments like IDs. It is not the
+			// macaron route pattern.
+			span.SetAttributes(
+				attribute.String("grafana.route_operation", routeOperation),
+				attribute.String("http.route", routeOperation),
+			)
 		}
 
 		status := rw.Status()
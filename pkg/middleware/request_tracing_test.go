@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"gopkg.in/macaron.v1"
+)
+
+// withRecordedSpans installs an SDK TracerProvider backed by a
+// tracetest.SpanRecorder as the global provider for the duration of the
+// test, restoring the previous one on cleanup. tracing.Tracer is built from
+// otel.Tracer(...), which always delegates to the current global provider,
+// so this captures every span RequestTracing starts without needing to
+// reach into pkg/infra/tracing directly.
+func withRecordedSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func newTracingTestServer(t *testing.T, opts ...TracingOption) *macaron.Macaron {
+	t.Helper()
+	m := macaron.New()
+	m.Use(RequestTracingWithOptions(opts...))
+	m.Use(ProvideRouteOperationName("test-route"))
+	m.Get("/api/test", func(res http.ResponseWriter, req *http.Request) {
+		res.WriteHeader(http.StatusOK)
+	})
+	return m
+}
+
+func TestRequestTracingWithOptions_AttributeExtractor(t *testing.T) {
+	var gotRequests []*http.Request
+	m := newTracingTestServer(t, WithAttributeExtractor(func(req *http.Request) []attribute.KeyValue {
+		gotRequests = append(gotRequests, req)
+		return []attribute.KeyValue{attribute.String("custom", "value")}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	require.Len(t, gotRequests, 1)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRequestTracingWithOptions_BaggageKeysAllowlist(t *testing.T) {
+	sr := withRecordedSpans(t)
+	m := newTracingTestServer(t, WithBaggageKeys("tenant"))
+
+	tenant, err := baggage.NewMember("tenant", "acme")
+	require.NoError(t, err)
+	other, err := baggage.NewMember("session", "not-allowlisted")
+	require.NoError(t, err)
+	bag, err := baggage.New(tenant, other)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	req = req.WithContext(baggage.ContextWithBaggage(req.Context(), bag))
+	rec := httptest.NewRecorder()
+
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, sr.Ended(), 1)
+	attrs := sr.Ended()[0].Attributes()
+	assert.Contains(t, attrs, attribute.String("baggage.tenant", "acme"))
+	for _, attr := range attrs {
+		assert.NotEqual(t, attribute.Key("baggage.session"), attr.Key, "baggage members outside the allowlist must not reach the span")
+	}
+}
+
+func TestRequestTracingWithOptions_Filter(t *testing.T) {
+	var traced bool
+	m := newTracingTestServer(t, WithAttributeExtractor(func(req *http.Request) []attribute.KeyValue {
+		traced = true
+		return nil
+	}), WithFilter(func(req *http.Request) bool {
+		return req.URL.Path != "/api/test"
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, traced, "filtered-out requests should never reach attribute extractors")
+}
+
+func TestRequestTracingWithOptions_PublicEndpoint(t *testing.T) {
+	sr := withRecordedSpans(t)
+	m := newTracingTestServer(t, WithPublicEndpointFn(func(req *http.Request) bool {
+		return req.URL.Path == "/api/test"
+	}))
+
+	const incomingTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	req.Header.Set("traceparent", "00-"+incomingTraceID+"-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	require.Len(t, sr.Ended(), 1)
+	span := sr.Ended()[0]
+	assert.NotEqual(t, incomingTraceID, span.SpanContext().TraceID().String(),
+		"a public-endpoint request must start a new root trace rather than adopting the untrusted incoming one")
+	require.Len(t, span.Links(), 1, "the incoming trace must still be linked so it can be correlated")
+	assert.Equal(t, incomingTraceID, span.Links()[0].SpanContext.TraceID().String())
+}
+
+func TestRequestTracingWithOptions_DecisionIDHeader(t *testing.T) {
+	m := newTracingTestServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get(DecisionIDHeaderName), "RequestTracing must set the decision ID response header on every traced request")
+}
+
+func TestSamplingRatioFromContext(t *testing.T) {
+	m := macaron.New()
+	m.Use(ProvideRouteSamplingRatio("test-route", 0.5))
+	m.Get("/api/test", func(res http.ResponseWriter, req *http.Request) {
+		ratio, ok := SamplingRatioFromContext(req.Context())
+		require.True(t, ok)
+		assert.InDelta(t, 0.5, ratio, 0.0001)
+		res.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/test", nil)
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -0,0 +1,51 @@
+package accesscontrol
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+)
+
+var auditLogger = log.New("accesscontrol.audit")
+
+// LogDecision writes a structured audit-log entry for a single
+// access-control evaluation, e.g. the allow/deny outcome of evaluating
+// ActionOrgsPreferencesRead against a request.
+//
+// decisionID ties the entry back to the request's trace (the
+// grafana.ac.decision_id span attribute set by middleware.RequestTracing)
+// and the X-Grafana-Decision-Id header returned to the caller, which is what
+// makes it possible to go from "viewer got 403 on /api/org/preferences" to
+// the exact evaluation that produced it. Callers get decisionID via
+// middleware.DecisionIDFromContext(ctx) - this package doesn't depend on
+// pkg/middleware directly, since middleware depends on accesscontrol for
+// permission checks and the reverse import would cycle.
+func LogDecision(ctx context.Context, decisionID, action string, allowed bool) {
+	auditLogger.Info("access-control decision",
+		"decisionId", decisionID,
+		"action", action,
+		"allowed", allowed,
+	)
+}
+
+// Evaluator is satisfied by the RBAC permission evaluators built with
+// EvalPermission and friends: given the permissions granted to the signed-in
+// user, it reports whether they satisfy the evaluator's condition.
+type Evaluator interface {
+	Evaluate(permissions map[string][]string) bool
+}
+
+// Evaluate runs evaluator against permissions and writes an audit-log entry
+// recording the allow/deny outcome for action via LogDecision, tagged with
+// decisionID.
+//
+// HTTP handlers that gate a response on an RBAC check - e.g.
+// GetCurrentOrgPreferences evaluating ActionOrgsPreferencesRead before
+// serving /api/org/preferences - should call Evaluate rather than invoking
+// their Evaluator directly, so every allow/deny decision reaches the audit
+// log the same way.
+func Evaluate(ctx context.Context, decisionID, action string, evaluator Evaluator, permissions map[string][]string) bool {
+	allowed := evaluator.Evaluate(permissions)
+	LogDecision(ctx, decisionID, action, allowed)
+	return allowed
+}
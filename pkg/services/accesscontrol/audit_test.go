@@ -0,0 +1,49 @@
+package accesscontrol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogDecision(t *testing.T) {
+	// LogDecision has no observable return value; this just guards against
+	// a panic when decisionID is empty, which happens for any code path
+	// that evaluates access control outside of an HTTP request (e.g. a
+	// background job) where RequestTracing never ran.
+	LogDecision(context.Background(), "", "orgs.preferences:read", false)
+	LogDecision(context.Background(), "01H8X1Z9Q9T9X9Q9T9X9Q9T9X9", "orgs.preferences:read", true)
+}
+
+type evaluatorFunc func(permissions map[string][]string) bool
+
+func (f evaluatorFunc) Evaluate(permissions map[string][]string) bool { return f(permissions) }
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name        string
+		evaluator   Evaluator
+		wantAllowed bool
+	}{
+		{"evaluator allows", evaluatorFunc(func(map[string][]string) bool { return true }), true},
+		{"evaluator denies", evaluatorFunc(func(map[string][]string) bool { return false }), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			permissions := map[string][]string{"orgs:read": {"orgs:id:1"}}
+
+			var got map[string][]string
+			evaluator := evaluatorFunc(func(permissions map[string][]string) bool {
+				got = permissions
+				return tt.evaluator.Evaluate(permissions)
+			})
+
+			allowed := Evaluate(context.Background(), "01H8X1Z9Q9T9X9Q9T9X9Q9T9X9", "orgs.preferences:read", evaluator, permissions)
+
+			assert.Equal(t, tt.wantAllowed, allowed)
+			assert.Equal(t, permissions, got, "Evaluate must pass permissions through to the evaluator unchanged")
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package setting holds Grafana's runtime configuration, assembled from
+// defaults.ini, custom.ini and environment variable overrides.
+//
+// This file only defines the subset of Cfg touched by the tracing/CORS work
+// in pkg/middleware; the rest of Grafana's configuration surface lives
+// alongside it.
+package setting
+
+import (
+	"strings"
+
+	"gopkg.in/ini.v1"
+)
+
+// Cfg holds Grafana's runtime configuration.
+type Cfg struct {
+	Raw *ini.File
+
+	Tracing  TracingSettings
+	Security SecuritySettings
+}
+
+// TracingSettings holds [tracing.opentelemetry] configuration.
+type TracingSettings struct {
+	// PublicEndpoint marks every request as coming from an untrusted,
+	// public-facing endpoint: incoming trace context is linked rather than
+	// adopted as the parent of the request span. See
+	// middleware.WithPublicEndpoint.
+	PublicEndpoint bool
+	// PublicEndpointPathPrefixes restricts PublicEndpoint to matching path
+	// prefixes. Empty means every request is treated as public.
+	PublicEndpointPathPrefixes []string
+	// ExcludedRoutes is a list of regexes matched against the request path;
+	// a match means no span is created for the request at all. See
+	// middleware.WithFilter.
+	ExcludedRoutes []string
+}
+
+// readTracingSettings populates cfg.Tracing from [tracing.opentelemetry] in
+// cfg.Raw.
+func (cfg *Cfg) readTracingSettings() {
+	sec := cfg.Raw.Section("tracing.opentelemetry")
+
+	cfg.Tracing.PublicEndpoint = sec.Key("public_endpoint").MustBool(false)
+	cfg.Tracing.PublicEndpointPathPrefixes = splitSettingList(sec.Key("public_endpoint_path_prefixes").String())
+	cfg.Tracing.ExcludedRoutes = splitSettingList(sec.Key("excluded_routes").String())
+}
+
+// SecuritySettings holds the subset of [security] configuration touched by
+// middleware.CORS; the rest of [security] lives alongside it.
+type SecuritySettings struct {
+	// CORSAllowOrigins is the [security.cors] allow_origins allowlist, e.g.
+	// "https://example.org" or "*" for every origin.
+	CORSAllowOrigins []string
+	// CORSAllowMethods is the method list advertised on
+	// Access-Control-Allow-Methods for every preflight response.
+	CORSAllowMethods []string
+	// CORSAllowHeaders is the Access-Control-Allow-Headers allowlist.
+	CORSAllowHeaders []string
+	// CORSAllowCredentials sets Access-Control-Allow-Credentials.
+	CORSAllowCredentials bool
+}
+
+// readCORSSettings populates cfg.Security's CORS fields from
+// [security.cors] in cfg.Raw.
+func (cfg *Cfg) readCORSSettings() {
+	sec := cfg.Raw.Section("security.cors")
+
+	cfg.Security.CORSAllowOrigins = splitSettingList(sec.Key("allow_origins").String())
+	cfg.Security.CORSAllowMethods = splitSettingList(sec.Key("allow_methods").MustString("GET, HEAD, POST, PUT, PATCH, DELETE"))
+	cfg.Security.CORSAllowHeaders = splitSettingList(sec.Key("allow_headers").MustString("Origin, Content-Type, Accept, Authorization"))
+	cfg.Security.CORSAllowCredentials = sec.Key("allow_credentials").MustBool(false)
+}
+
+// splitSettingList splits a comma-separated .ini value into a trimmed,
+// non-empty slice of strings.
+func splitSettingList(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+
+	var out []string
+	for _, v := range strings.Split(raw, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
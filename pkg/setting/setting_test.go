@@ -0,0 +1,66 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+)
+
+func TestCfg_readTracingSettings(t *testing.T) {
+	raw, err := ini.Load([]byte(`
+[tracing.opentelemetry]
+public_endpoint = true
+public_endpoint_path_prefixes = /api/public/, /api/another/
+excluded_routes = ^/api/health$, ^/metrics$
+`))
+	require.NoError(t, err)
+
+	cfg := &Cfg{Raw: raw}
+	cfg.readTracingSettings()
+
+	assert.True(t, cfg.Tracing.PublicEndpoint)
+	assert.Equal(t, []string{"/api/public/", "/api/another/"}, cfg.Tracing.PublicEndpointPathPrefixes)
+	assert.Equal(t, []string{"^/api/health$", "^/metrics$"}, cfg.Tracing.ExcludedRoutes)
+}
+
+func TestCfg_readTracingSettings_defaults(t *testing.T) {
+	raw, err := ini.Load([]byte(``))
+	require.NoError(t, err)
+
+	cfg := &Cfg{Raw: raw}
+	cfg.readTracingSettings()
+
+	assert.False(t, cfg.Tracing.PublicEndpoint)
+	assert.Empty(t, cfg.Tracing.PublicEndpointPathPrefixes)
+}
+
+func TestCfg_readCORSSettings(t *testing.T) {
+	raw, err := ini.Load([]byte(`
+[security.cors]
+allow_origins = https://example.org, https://grafana.example.org
+allow_headers = Origin, Content-Type
+allow_credentials = true
+`))
+	require.NoError(t, err)
+
+	cfg := &Cfg{Raw: raw}
+	cfg.readCORSSettings()
+
+	assert.Equal(t, []string{"https://example.org", "https://grafana.example.org"}, cfg.Security.CORSAllowOrigins)
+	assert.Equal(t, []string{"Origin", "Content-Type"}, cfg.Security.CORSAllowHeaders)
+	assert.True(t, cfg.Security.CORSAllowCredentials)
+	assert.NotEmpty(t, cfg.Security.CORSAllowMethods, "falls back to the default method list")
+}
+
+func TestCfg_readCORSSettings_defaults(t *testing.T) {
+	raw, err := ini.Load([]byte(``))
+	require.NoError(t, err)
+
+	cfg := &Cfg{Raw: raw}
+	cfg.readCORSSettings()
+
+	assert.Empty(t, cfg.Security.CORSAllowOrigins)
+	assert.False(t, cfg.Security.CORSAllowCredentials)
+}